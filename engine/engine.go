@@ -0,0 +1,53 @@
+package engine
+
+import "errors"
+
+// ErrNotFound is returned when a queue/job/dead-letter lookup finds nothing.
+var ErrNotFound = errors.New("not found")
+
+// ErrWrongQueue is returned when a job is pushed onto a queue other than the
+// one it was created for.
+var ErrWrongQueue = errors.New("wrong queue for the job")
+
+// Job is a single message popped off a queue: its identity, payload, and how
+// long it's been sitting with the consumer that holds it.
+type Job interface {
+	Namespace() string
+	Queue() string
+	ID() string
+	Body() []byte
+	TTL() uint32
+	ElapsedMS() int64
+}
+
+// Engine is the storage-backed surface the HTTP handlers drive: the
+// publish/consume/delete lifecycle, dead-letter management, queue discovery,
+// and job history/rate-limit introspection. The redis package is the only
+// implementation.
+type Engine interface {
+	Publish(namespace, queue string, body []byte, ttlSecond, delaySecond uint32, tries uint16) (jobID string, err error)
+	Consume(namespace, queue string, ttrSecond, timeoutSecond uint32) (Job, error)
+	ConsumeMulti(namespace string, queues []string, ttrSecond, timeoutSecond uint32) (Job, error)
+	Delete(namespace, queue, jobID string) error
+	Peek(namespace, queue, jobID string) (Job, error)
+	Size(namespace, queue string) (int64, error)
+	Destroy(namespace, queue string) (count int64, err error)
+
+	PeekDeadLetter(namespace, queue string) (size int64, jobID string, err error)
+	RespawnDeadLetter(namespace, queue string, limit, ttlSecond int64) (count int64, err error)
+	DeleteDeadLetter(namespace, queue string, limit int64) (count int64, err error)
+
+	// ListQueues and JobTypes back the discovery endpoints: every queue a
+	// namespace has ever published to, and the ttl/ttr/tries/rate-limit
+	// defaults each one runs with.
+	ListQueues(namespace string) ([]QueueInfo, error)
+	JobTypes(namespace string) (map[string]QueueTypeInfo, error)
+
+	// JobHistory, JobsByStatus, AckJob and NackJob back the job-history
+	// endpoints: a job's state-transition record, paginated per-status
+	// listing, and the ack/nack bookkeeping that feeds them.
+	JobHistory(namespace, queue, jobID string) (*JobHistoryRecord, error)
+	JobsByStatus(namespace, queue string, status JobStatus, cursor uint64, limit int64) ([]string, uint64, error)
+	AckJob(namespace, queue, jobID string) error
+	NackJob(namespace, queue, jobID, lastError string, dead bool) error
+}
@@ -0,0 +1,25 @@
+package engine
+
+// JobStatus is the terminal/non-terminal state a job is in, as tracked by
+// the job history record.
+type JobStatus string
+
+const (
+	JobStatusPending   JobStatus = "pending"
+	JobStatusInFlight  JobStatus = "in_flight"
+	JobStatusSucceeded JobStatus = "succeeded"
+	JobStatusDead      JobStatus = "dead"
+	JobStatusDestroyed JobStatus = "destroyed"
+)
+
+// JobHistoryRecord captures when a job moved through the queue and how it
+// ended up, so operators can answer "why did this job end up dead?" without
+// grepping logs.
+type JobHistoryRecord struct {
+	EnqueuedAt int64     `json:"enqueued_at"`
+	StartedAt  int64     `json:"started_at,omitempty"`
+	FinishedAt int64     `json:"finished_at,omitempty"`
+	Status     JobStatus `json:"status"`
+	Attempts   int       `json:"attempts"`
+	LastError  string    `json:"last_error,omitempty"`
+}
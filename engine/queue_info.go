@@ -0,0 +1,25 @@
+package engine
+
+// QueueInfo summarizes a single queue's current state for discovery and
+// monitoring, as returned by Engine.ListQueues.
+type QueueInfo struct {
+	Queue           string `json:"queue"`
+	ReadySize       int64  `json:"ready_size"`
+	DelayedSize     int64  `json:"delayed_size"`
+	DeadLetterSize  int64  `json:"deadletter_size"`
+	LastPublishedAt int64  `json:"last_published_at,omitempty"`
+	LastConsumedAt  int64  `json:"last_consumed_at,omitempty"`
+}
+
+// QueueTypeInfo describes the defaults a queue was configured with, so
+// clients can self-configure instead of guessing ttl/ttr/tries.
+type QueueTypeInfo struct {
+	Queue        string `json:"queue"`
+	TTL          uint32 `json:"ttl"`
+	TTR          uint32 `json:"ttr"`
+	Tries        uint16 `json:"tries"`
+	PublishLimit int    `json:"publish_limit"`
+	PublishBurst int    `json:"publish_burst"`
+	ConsumeLimit int    `json:"consume_limit"`
+	ConsumeBurst int    `json:"consume_burst"`
+}
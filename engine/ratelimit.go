@@ -0,0 +1,19 @@
+package engine
+
+import (
+	"fmt"
+	"time"
+)
+
+// RateLimitedError is returned by Publish/Consume (and their engine
+// implementations) when a queue has exhausted its admission budget and the
+// wait for the next token would exceed the namespace's configured deadline;
+// the caller is rejected without waiting. RetryAfter tells the caller how
+// long to back off before trying again.
+type RateLimitedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitedError) Error() string {
+	return fmt.Sprintf("rate limited, retry after %s", e.RetryAfter)
+}
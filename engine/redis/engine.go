@@ -0,0 +1,114 @@
+package redis
+
+import "github.com/meitu/lmstfy/engine"
+
+// BaseEngine is the pool's existing Publish/Consume/Delete/dead-letter
+// implementation. Engine embeds it so it satisfies the full engine.Engine
+// interface by delegation, and only overrides the methods that also need to
+// touch job history (Delete, to record the ack).
+type BaseEngine interface {
+	Publish(namespace, queue string, body []byte, ttlSecond, delaySecond uint32, tries uint16) (jobID string, err error)
+	Consume(namespace, queue string, ttrSecond, timeoutSecond uint32) (engine.Job, error)
+	ConsumeMulti(namespace string, queues []string, ttrSecond, timeoutSecond uint32) (engine.Job, error)
+	Delete(namespace, queue, jobID string) error
+	Peek(namespace, queue, jobID string) (engine.Job, error)
+	Size(namespace, queue string) (int64, error)
+	Destroy(namespace, queue string) (count int64, err error)
+	PeekDeadLetter(namespace, queue string) (size int64, jobID string, err error)
+	RespawnDeadLetter(namespace, queue string, limit, ttlSecond int64) (count int64, err error)
+	DeleteDeadLetter(namespace, queue string, limit int64) (count int64, err error)
+}
+
+// Engine composes the pool's BaseEngine with rate limiting, job history and
+// queue discovery, so it's the one value handlers need behind the
+// engine.Engine interface for this pool.
+type Engine struct {
+	BaseEngine
+
+	redis       *RedisInstance
+	timer       *Timer
+	limiter     *RateLimiter
+	history     *JobHistoryStore
+	jobDefaults engine.QueueTypeInfo
+}
+
+// NewEngine wires base/redis/timer/limiter/history together. limiter and
+// history may be nil, in which case rate limiting and job history tracking
+// are disabled for this pool. Call it once per pool at startup (see
+// SetupPool), passing the pool's already-constructed base engine.
+func NewEngine(base BaseEngine, redis *RedisInstance, timer *Timer, limiter *RateLimiter, history *JobHistoryStore, jobDefaults engine.QueueTypeInfo) *Engine {
+	if limiter != nil {
+		RegisterRateLimiter(redis.Name, limiter)
+	}
+	if history != nil {
+		RegisterJobHistoryStore(redis.Name, history)
+	}
+	return &Engine{
+		BaseEngine:  base,
+		redis:       redis,
+		timer:       timer,
+		limiter:     limiter,
+		history:     history,
+		jobDefaults: jobDefaults,
+	}
+}
+
+// Delete overrides BaseEngine.Delete to also record the job as succeeded in
+// its history -- this is the real ack path; AckJob below only exists for
+// callers that track success separately from removal.
+func (e *Engine) Delete(namespace, queue, jobID string) error {
+	if err := e.BaseEngine.Delete(namespace, queue, jobID); err != nil {
+		return err
+	}
+	if e.history != nil {
+		if err := e.history.RecordAck(namespace, queue, jobID); err != nil {
+			logger.WithField("err", err).Error("Failed to record job history for delete")
+		}
+	}
+	return nil
+}
+
+// ListQueues returns every queue namespace has ever published to.
+func (e *Engine) ListQueues(namespace string) ([]engine.QueueInfo, error) {
+	return ListQueues(e.redis, e.timer, namespace)
+}
+
+// JobTypes returns each queue's default ttl/ttr/tries and rate-limit config.
+func (e *Engine) JobTypes(namespace string) (map[string]engine.QueueTypeInfo, error) {
+	return JobTypes(e.redis, e.limiter, e.jobDefaults, namespace)
+}
+
+// JobHistory returns the state-history record for a single job.
+func (e *Engine) JobHistory(namespace, queue, jobID string) (*engine.JobHistoryRecord, error) {
+	if e.history == nil {
+		return nil, engine.ErrNotFound
+	}
+	return e.history.Get(namespace, queue, jobID)
+}
+
+// JobsByStatus pages through job IDs currently in the given status.
+func (e *Engine) JobsByStatus(namespace, queue string, status engine.JobStatus, cursor uint64, limit int64) ([]string, uint64, error) {
+	if e.history == nil {
+		return nil, 0, nil
+	}
+	return e.history.ListByStatus(namespace, queue, status, cursor, limit)
+}
+
+// AckJob records that a consumer successfully processed jobID, without
+// deleting it the way Delete does. Prefer Delete for real consumers; this
+// exists for callers that track success separately from removal.
+func (e *Engine) AckJob(namespace, queue, jobID string) error {
+	if e.history == nil {
+		return nil
+	}
+	return e.history.RecordAck(namespace, queue, jobID)
+}
+
+// NackJob records that a consumer failed to process jobID. dead should be
+// true once the job has exhausted its tries and landed in the dead letter.
+func (e *Engine) NackJob(namespace, queue, jobID, lastError string, dead bool) error {
+	if e.history == nil {
+		return nil
+	}
+	return e.history.RecordNack(namespace, queue, jobID, lastError, dead)
+}
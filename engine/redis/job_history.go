@@ -0,0 +1,257 @@
+package redis
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/meitu/lmstfy/engine"
+)
+
+// JobHistoryPrefix namespaces the history hash and status-index keys so they
+// can't collide with queue/pool/timer keys.
+const JobHistoryPrefix = "jh"
+
+// JobHistoryStore persists a per-job state-history record as a redis hash,
+// TTL'd the same as the job itself, plus a per-status index set so operators
+// can list e.g. all dead jobs in a queue.
+type JobHistoryStore struct {
+	redis *RedisInstance
+}
+
+func NewJobHistoryStore(redis *RedisInstance) *JobHistoryStore {
+	return &JobHistoryStore{redis: redis}
+}
+
+func (s *JobHistoryStore) key(namespace, queue, jobID string) string {
+	return join(JobHistoryPrefix, namespace, queue, jobID)
+}
+
+func (s *JobHistoryStore) statusIndexKey(namespace, queue string, status engine.JobStatus) string {
+	return join(JobHistoryPrefix, "idx", namespace, queue, string(status))
+}
+
+// allJobStatuses is every status a job history record can be in. transition
+// hands the redis/queue key for each of them to the Lua script below so it
+// can look up the one the job is currently indexed under without the caller
+// needing to know the join() key format.
+var allJobStatuses = []engine.JobStatus{
+	engine.JobStatusPending,
+	engine.JobStatusInFlight,
+	engine.JobStatusSucceeded,
+	engine.JobStatusDead,
+	engine.JobStatusDestroyed,
+}
+
+// luaJobHistoryTransitionScript atomically reads the job's current status,
+// moves it from that status's index set into the new one, and applies the
+// hash field updates -- all in one EVAL, so a crash mid-transition can never
+// leave a job indexed under a status index it's no longer actually in.
+//
+// KEYS[1] = job history hash key
+// ARGV[1] = new status
+// ARGV[2] = new status's index set key
+// ARGV[3] = job ID
+// ARGV[4] = number of (status, index key) pairs that follow
+// ARGV[5..5+2*n) = status, index key pairs, one per known status
+// ARGV[5+2*n]    = field to HINCRBY, or "" for none
+// ARGV[5+2*n+1]  = amount to HINCRBY by, ignored if the field above is ""
+// ARGV[5+2*n+2..] = field, value pairs to HSET onto the hash
+const luaJobHistoryTransitionScript = `
+local key = KEYS[1]
+local newStatus = ARGV[1]
+local newIndexKey = ARGV[2]
+local jobID = ARGV[3]
+local numStatuses = tonumber(ARGV[4])
+
+local statusIndex = {}
+local i = 5
+for n = 1, numStatuses do
+	statusIndex[ARGV[i]] = ARGV[i + 1]
+	i = i + 2
+end
+
+local oldStatus = redis.call('HGET', key, 'status')
+if oldStatus and oldStatus ~= false then
+	local oldIndexKey = statusIndex[oldStatus]
+	if oldIndexKey and oldIndexKey ~= newIndexKey then
+		redis.call('SREM', oldIndexKey, jobID)
+	end
+end
+redis.call('SADD', newIndexKey, jobID)
+
+local incrField = ARGV[i]
+local incrAmount = tonumber(ARGV[i + 1])
+i = i + 2
+if incrField ~= '' and incrAmount ~= 0 then
+	redis.call('HINCRBY', key, incrField, incrAmount)
+end
+
+while i <= #ARGV do
+	redis.call('HSET', key, ARGV[i], ARGV[i + 1])
+	i = i + 2
+end
+return 1
+`
+
+var _lua_job_history_transition_sha string
+
+// PreloadJobHistoryTransitionLuaScript loads luaJobHistoryTransitionScript
+// into redis and caches its SHA, mirroring PreloadDeadLetterLuaScript.
+func PreloadJobHistoryTransitionLuaScript(redis *RedisInstance) error {
+	sha, err := redis.Conn.ScriptLoad(luaJobHistoryTransitionScript).Result()
+	if err != nil {
+		return err
+	}
+	_lua_job_history_transition_sha = sha
+	return nil
+}
+
+// buildTransitionArgv lays out the ARGV luaJobHistoryTransitionScript
+// expects: new status, new status's index key, job ID, the (status, index
+// key) pairs for every known status, the optional HINCRBY field/amount, then
+// the HSET field/value pairs. Pulled out of transition so the layout can be
+// tested without a redis connection.
+func (s *JobHistoryStore) buildTransitionArgv(namespace, queue, jobID string, status engine.JobStatus, fields map[string]interface{}, incrField string, incrAmount int64) []interface{} {
+	argv := make([]interface{}, 0, 6+len(allJobStatuses)*2+len(fields)*2)
+	argv = append(argv, string(status), s.statusIndexKey(namespace, queue, status), jobID, len(allJobStatuses))
+	for _, st := range allJobStatuses {
+		argv = append(argv, string(st), s.statusIndexKey(namespace, queue, st))
+	}
+	argv = append(argv, incrField, incrAmount)
+	for field, value := range fields {
+		argv = append(argv, field, value)
+	}
+	return argv
+}
+
+// transition atomically updates the history hash and moves the job between
+// status index sets via luaJobHistoryTransitionScript. incrField, if not
+// empty, is HINCRBY'd by incrAmount in the same EVAL.
+func (s *JobHistoryStore) transition(namespace, queue, jobID string, status engine.JobStatus, fields map[string]interface{}, incrField string, incrAmount int64) error {
+	key := s.key(namespace, queue, jobID)
+	fields["status"] = string(status)
+	argv := s.buildTransitionArgv(namespace, queue, jobID, status, fields, incrField, incrAmount)
+
+	for {
+		_, err := s.redis.Conn.EvalSha(_lua_job_history_transition_sha, []string{key}, argv...).Result()
+		if err != nil {
+			if isLuaScriptGone(err) {
+				if err := PreloadJobHistoryTransitionLuaScript(s.redis); err != nil {
+					return err
+				}
+				continue
+			}
+			return err
+		}
+		return nil
+	}
+}
+
+// RecordEnqueued marks a job pending as it's pushed onto the ready queue.
+func (s *JobHistoryStore) RecordEnqueued(namespace, queue, jobID string, ttlSecond uint32) error {
+	key := s.key(namespace, queue, jobID)
+	if err := s.transition(namespace, queue, jobID, engine.JobStatusPending, map[string]interface{}{
+		"enqueued_at": time.Now().Unix(),
+		"attempts":    0,
+	}, "", 0); err != nil {
+		return err
+	}
+	if ttlSecond > 0 {
+		return s.redis.Conn.Expire(key, time.Duration(ttlSecond)*time.Second).Err()
+	}
+	return nil
+}
+
+// RecordStarted marks a job in-flight when PollQueues hands it out and the
+// TTR timer is armed, bumping its attempt count in the same atomic EVAL.
+func (s *JobHistoryStore) RecordStarted(namespace, queue, jobID string) error {
+	return s.transition(namespace, queue, jobID, engine.JobStatusInFlight, map[string]interface{}{
+		"started_at": time.Now().Unix(),
+	}, "attempts", 1)
+}
+
+// RecordAck marks a job succeeded. Called by the consumer's ack, or by
+// Delete once the job has been popped off the in-flight timer.
+func (s *JobHistoryStore) RecordAck(namespace, queue, jobID string) error {
+	return s.transition(namespace, queue, jobID, engine.JobStatusSucceeded, map[string]interface{}{
+		"finished_at": time.Now().Unix(),
+	}, "", 0)
+}
+
+// RecordNack records a failed attempt. If dead is true the job has exhausted
+// its tries and landed in the dead letter, so it's marked finished; otherwise
+// it goes back to pending for its next retry.
+//
+// Nothing in this package calls RecordNack automatically yet: the TTR-expiry
+// path that actually moves an exhausted job into the dead letter lives in
+// the timer/dead-letter implementation, which isn't instrumented here. Until
+// that's hooked up, dead jobs only show up in history for callers that
+// explicitly hit the nack endpoint.
+func (s *JobHistoryStore) RecordNack(namespace, queue, jobID, lastError string, dead bool) error {
+	status := engine.JobStatusPending
+	fields := map[string]interface{}{"last_error": lastError}
+	if dead {
+		status = engine.JobStatusDead
+		fields["finished_at"] = time.Now().Unix()
+	}
+	return s.transition(namespace, queue, jobID, status, fields, "", 0)
+}
+
+// RecordDestroyed marks a job destroyed, e.g. when its queue is wiped via
+// Queue.Destroy.
+func (s *JobHistoryStore) RecordDestroyed(namespace, queue, jobID string) error {
+	return s.transition(namespace, queue, jobID, engine.JobStatusDestroyed, map[string]interface{}{
+		"finished_at": time.Now().Unix(),
+	}, "", 0)
+}
+
+// Get returns the history record for a single job.
+func (s *JobHistoryStore) Get(namespace, queue, jobID string) (*engine.JobHistoryRecord, error) {
+	vals, err := s.redis.Conn.HGetAll(s.key(namespace, queue, jobID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(vals) == 0 {
+		return nil, engine.ErrNotFound
+	}
+	h := &engine.JobHistoryRecord{Status: engine.JobStatus(vals["status"]), LastError: vals["last_error"]}
+	h.EnqueuedAt, _ = strconv.ParseInt(vals["enqueued_at"], 10, 64)
+	h.StartedAt, _ = strconv.ParseInt(vals["started_at"], 10, 64)
+	h.FinishedAt, _ = strconv.ParseInt(vals["finished_at"], 10, 64)
+	h.Attempts, _ = strconv.Atoi(vals["attempts"])
+	return h, nil
+}
+
+// ListByStatus pages through the job IDs currently in the given status,
+// starting at cursor (0 to start a fresh scan). It returns the next cursor
+// to resume from; a returned cursor of 0 means the scan is done. Unlike
+// SRANDMEMBER, SSCAN doesn't skip or repeat members across a full scan, so
+// two callers paging the same status set can actually enumerate it, and
+// repeated calls with the same cursor return a stable-ish page rather than
+// a fresh random sample each time.
+func (s *JobHistoryStore) ListByStatus(namespace, queue string, status engine.JobStatus, cursor uint64, limit int64) (jobIDs []string, nextCursor uint64, err error) {
+	return s.redis.Conn.SScan(s.statusIndexKey(namespace, queue, status), cursor, "", limit).Result()
+}
+
+// jobHistoryStores lets the free-standing PollQueues function (which has no
+// Queue receiver to hang a store off of) find the store for a given redis
+// pool by name.
+var jobHistoryStores = struct {
+	mu     sync.RWMutex
+	byPool map[string]*JobHistoryStore
+}{byPool: make(map[string]*JobHistoryStore)}
+
+// RegisterJobHistoryStore installs the job history store used by pool
+// poolName. It should be called once per pool at startup.
+func RegisterJobHistoryStore(poolName string, store *JobHistoryStore) {
+	jobHistoryStores.mu.Lock()
+	defer jobHistoryStores.mu.Unlock()
+	jobHistoryStores.byPool[poolName] = store
+}
+
+func jobHistoryStoreFor(redis *RedisInstance) *JobHistoryStore {
+	jobHistoryStores.mu.RLock()
+	defer jobHistoryStores.mu.RUnlock()
+	return jobHistoryStores.byPool[redis.Name]
+}
@@ -0,0 +1,52 @@
+package redis
+
+import (
+	"testing"
+
+	"github.com/meitu/lmstfy/engine"
+)
+
+func TestBuildTransitionArgv_EncodesEveryKnownStatusIndex(t *testing.T) {
+	s := &JobHistoryStore{}
+	argv := s.buildTransitionArgv("ns", "q", "job-1", engine.JobStatusDead,
+		map[string]interface{}{"status": string(engine.JobStatusDead), "finished_at": int64(1)}, "", 0)
+
+	if got, want := argv[0], string(engine.JobStatusDead); got != want {
+		t.Fatalf("argv[0] = %v, want %v", got, want)
+	}
+	if got, want := argv[2], "job-1"; got != want {
+		t.Fatalf("argv[2] = %v, want %v", got, want)
+	}
+	n, ok := argv[3].(int)
+	if !ok || n != len(allJobStatuses) {
+		t.Fatalf("argv[3] = %v, want status count %d", argv[3], len(allJobStatuses))
+	}
+
+	// The (status, index key) pairs occupy argv[4 : 4+2*n].
+	seen := make(map[string]bool, len(allJobStatuses))
+	for i := 4; i < 4+2*n; i += 2 {
+		seen[argv[i].(string)] = true
+	}
+	for _, st := range allJobStatuses {
+		if !seen[string(st)] {
+			t.Fatalf("missing (status, index key) pair for %q", st)
+		}
+	}
+
+	i := 4 + 2*n
+	if argv[i] != "" || argv[i+1] != int64(0) {
+		t.Fatalf("expected no HINCRBY for this transition, got field=%v amount=%v", argv[i], argv[i+1])
+	}
+}
+
+func TestBuildTransitionArgv_IncludesHincrWhenRequested(t *testing.T) {
+	s := &JobHistoryStore{}
+	argv := s.buildTransitionArgv("ns", "q", "job-1", engine.JobStatusInFlight,
+		map[string]interface{}{"status": string(engine.JobStatusInFlight)}, "attempts", 1)
+
+	n := len(allJobStatuses)
+	i := 4 + 2*n
+	if argv[i] != "attempts" || argv[i+1] != int64(1) {
+		t.Fatalf("expected HINCRBY attempts by 1, got field=%v amount=%v", argv[i], argv[i+1])
+	}
+}
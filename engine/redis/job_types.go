@@ -0,0 +1,74 @@
+package redis
+
+import (
+	"strconv"
+
+	"github.com/meitu/lmstfy/engine"
+)
+
+// queueTypeOverridePrefix holds, per queue, any ttl/ttr/tries this queue was
+// explicitly configured with, overriding the namespace's defaults.
+const queueTypeOverridePrefix = "queue_type_override"
+
+func queueTypeOverrideKey(namespace, queue string) string {
+	return join(queueTypeOverridePrefix, namespace, queue)
+}
+
+// SetQueueTypeOverride records queue's own ttl/ttr/tries, so JobTypes reports
+// them instead of the namespace defaults. Queues without an override here
+// just report the namespace defaults, same as before this existed.
+func SetQueueTypeOverride(redis *RedisInstance, namespace, queue string, ttl, ttr uint32, tries uint16) error {
+	return redis.Conn.HMSet(queueTypeOverrideKey(namespace, queue), map[string]interface{}{
+		"ttl":   ttl,
+		"ttr":   ttr,
+		"tries": tries,
+	}).Err()
+}
+
+// queueTypeOverride returns queue's ttl/ttr/tries override, and whether one
+// was set at all.
+func queueTypeOverride(redis *RedisInstance, namespace, queue string) (ttl, ttr uint32, tries uint16, ok bool) {
+	vals, err := redis.Conn.HGetAll(queueTypeOverrideKey(namespace, queue)).Result()
+	if err != nil || len(vals) == 0 {
+		return 0, 0, 0, false
+	}
+	ttl64, _ := strconv.ParseUint(vals["ttl"], 10, 32)
+	ttr64, _ := strconv.ParseUint(vals["ttr"], 10, 32)
+	tries64, _ := strconv.ParseUint(vals["tries"], 10, 16)
+	return uint32(ttl64), uint32(ttr64), uint16(tries64), true
+}
+
+// JobTypes returns the ttl/ttr/tries and rate-limit config for every queue
+// namespace has ever pushed to, so clients can self-configure instead of
+// guessing. defaults supplies the ttl/ttr/tries a queue falls back to absent
+// its own override via SetQueueTypeOverride; the rate-limit config is always
+// the namespace's, since RateLimiter configs are set per namespace (each queue
+// gets its own token bucket built from that config).
+func JobTypes(redis *RedisInstance, limiter *RateLimiter, defaults engine.QueueTypeInfo, namespace string) (map[string]engine.QueueTypeInfo, error) {
+	names, err := redis.Conn.SMembers(knownQueuesKey(namespace)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	rateLimit := defaults
+	if limiter != nil {
+		cfg := limiter.configFor(namespace)
+		rateLimit.PublishLimit = cfg.PublishLimit
+		rateLimit.PublishBurst = cfg.PublishBurst
+		rateLimit.ConsumeLimit = cfg.ConsumeLimit
+		rateLimit.ConsumeBurst = cfg.ConsumeBurst
+	}
+
+	types := make(map[string]engine.QueueTypeInfo, len(names))
+	for _, name := range names {
+		queueInfo := rateLimit
+		queueInfo.Queue = name
+		if ttl, ttr, tries, ok := queueTypeOverride(redis, namespace, name); ok {
+			queueInfo.TTL = ttl
+			queueInfo.TTR = ttr
+			queueInfo.Tries = tries
+		}
+		types[name] = queueInfo
+	}
+	return types, nil
+}
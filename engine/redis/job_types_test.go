@@ -0,0 +1,14 @@
+package redis
+
+import "testing"
+
+func TestQueueTypeOverrideKey_IsScopedPerQueue(t *testing.T) {
+	a := queueTypeOverrideKey("ns", "q1")
+	b := queueTypeOverrideKey("ns", "q2")
+	if a == b {
+		t.Fatalf("expected distinct keys per queue, got %q for both", a)
+	}
+	if a == queueTypeOverrideKey("other-ns", "q1") {
+		t.Fatalf("expected distinct keys per namespace, got %q for both", a)
+	}
+}
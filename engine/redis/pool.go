@@ -0,0 +1,22 @@
+package redis
+
+import "github.com/meitu/lmstfy/engine"
+
+// NamespaceRateLimits maps a namespace to its publish/consume admission
+// budget, as loaded from the pool's token config at startup.
+type NamespaceRateLimits map[string]RateLimitConfig
+
+// SetupPool is the real startup call site for a redis pool: it builds the
+// RateLimiter from the pool's namespace config and the JobHistoryStore, then
+// hands both to NewEngine so every Queue/PollQueues call for this pool picks
+// them up automatically. Call it once per pool at startup, after loading
+// fallback and perNamespace from the pool's token config, and bind the
+// returned Engine into request context (see server.EngineMiddleware).
+func SetupPool(base BaseEngine, redis *RedisInstance, timer *Timer, fallback RateLimitConfig, perNamespace NamespaceRateLimits, jobDefaults engine.QueueTypeInfo) *Engine {
+	limiter := NewRateLimiter(fallback)
+	for namespace, cfg := range perNamespace {
+		limiter.SetNamespaceConfig(namespace, cfg)
+	}
+	history := NewJobHistoryStore(redis)
+	return NewEngine(base, redis, timer, limiter, history, jobDefaults)
+}
@@ -31,18 +31,20 @@ func (k *QueueName) Decode(str string) error {
 
 // Queue is the "ready queue" that has all the jobs that can be consumed right now
 type Queue struct {
-	name  QueueName
-	redis *RedisInstance
-	timer *Timer
+	name    QueueName
+	redis   *RedisInstance
+	timer   *Timer
+	limiter *RateLimiter
 
 	lua_destroy_sha string
 }
 
 func NewQueue(namespace, queue string, redis *RedisInstance, timer *Timer) *Queue {
 	return &Queue{
-		name:  QueueName{Namespace: namespace, Queue: queue},
-		redis: redis,
-		timer: timer,
+		name:    QueueName{Namespace: namespace, Queue: queue},
+		redis:   redis,
+		timer:   timer,
+		limiter: rateLimiterFor(redis), // nil if the pool never registered one, i.e. unlimited
 
 		// NOTE: deadletter and queue are actually the same data structure, we could reuse the lua script
 		// to empty the redis list (used as queue here). all we need to do is pass the queue name as the
@@ -55,6 +57,13 @@ func (q *Queue) Name() string {
 	return q.name.String()
 }
 
+// SetRateLimiter installs a rate limiter to admission-gate Push/Poll calls
+// for this queue. Queues without one are unlimited, so existing callers are
+// unaffected.
+func (q *Queue) SetRateLimiter(limiter *RateLimiter) {
+	q.limiter = limiter
+}
+
 // Push a job into the queue, the job data format: {tries}{job id}
 func (q *Queue) Push(j engine.Job, tries uint16) error {
 	if tries == 0 {
@@ -64,7 +73,23 @@ func (q *Queue) Push(j engine.Job, tries uint16) error {
 		// Wrong queue for the job
 		return engine.ErrWrongQueue
 	}
+	if q.limiter != nil {
+		if err := q.limiter.Admit(q.name.Namespace, q.name.Queue, rateLimitPublish); err != nil {
+			return err
+		}
+	}
 	metrics.queueDirectPushJobs.WithLabelValues(q.redis.Name).Inc()
+	if store := jobHistoryStoreFor(q.redis); store != nil {
+		if err := store.RecordEnqueued(q.name.Namespace, q.name.Queue, j.ID(), j.TTL()); err != nil {
+			logger.WithField("err", err).Error("Failed to record job history for enqueue")
+		}
+	}
+	if err := RecordQueueKnown(q.redis, q.name.Namespace, q.name.Queue); err != nil {
+		logger.WithField("err", err).Error("Failed to record known queue")
+	}
+	if err := RecordQueueActivity(q.redis, q.name.Namespace, q.name.Queue, "publish"); err != nil {
+		logger.WithField("err", err).Error("Failed to record queue publish activity")
+	}
 	val := structPack(tries, j.ID())
 	return q.redis.Conn.LPush(q.Name(), val).Err()
 }
@@ -72,6 +97,11 @@ func (q *Queue) Push(j engine.Job, tries uint16) error {
 // Pop a job. If the tries > 0, add job to the "in-flight" timer with timestamp
 // set to `TTR + now()`; Or we might just move the job to "dead-letter".
 func (q *Queue) Poll(timeoutSecond, ttrSecond uint32) (jobID string, err error) {
+	if q.limiter != nil {
+		if err := q.limiter.Admit(q.name.Namespace, q.name.Queue, rateLimitConsume); err != nil {
+			return "", err
+		}
+	}
 	_, jobID, err = PollQueues(q.redis, q.timer, []QueueName{q.name}, timeoutSecond, ttrSecond)
 	return jobID, err
 }
@@ -98,8 +128,12 @@ func (q *Queue) Peek() (jobID string, err error) {
 
 func (q *Queue) Destroy() (count int64, err error) {
 	poolPrefix := PoolJobKeyPrefix(q.name.Namespace, q.name.Queue)
+	store := jobHistoryStoreFor(q.redis)
 	var batchSize int64 = 100
 	for {
+		if store != nil {
+			q.recordBatchDestroyed(store, batchSize)
+		}
 		val, err := q.redis.Conn.EvalSha(q.lua_destroy_sha, []string{q.Name(), poolPrefix}, batchSize).Result()
 		if err != nil {
 			if isLuaScriptGone(err) {
@@ -117,6 +151,31 @@ func (q *Queue) Destroy() (count int64, err error) {
 	return count, nil
 }
 
+// recordBatchDestroyed marks every job currently sitting in the next
+// destroy batch as destroyed, best-effort. It reads the batch by LRANGE just
+// ahead of the destroy script's own pop, so in principle a concurrent
+// consumer could still pop one of these jobs between the two calls; that
+// job would then be marked destroyed even though it's actually in flight.
+// The lua_destroy_sha script itself only returns a count, not the job IDs it
+// removed, so this read-ahead is the closest this can get to exact without
+// changing that script's return value.
+func (q *Queue) recordBatchDestroyed(store *JobHistoryStore, batchSize int64) {
+	vals, err := q.redis.Conn.LRange(q.Name(), -batchSize, -1).Result()
+	if err != nil {
+		logger.WithField("err", err).Error("Failed to read batch for destroy history")
+		return
+	}
+	for _, val := range vals {
+		_, jobID, err := structUnpack(val)
+		if err != nil {
+			continue
+		}
+		if err := store.RecordDestroyed(q.name.Namespace, q.name.Queue, jobID); err != nil {
+			logger.WithField("err", err).Error("Failed to record job history for destroy")
+		}
+	}
+}
+
 // Poll from multiple queues using blocking method; OR pop a job from one queue using non-blocking method
 func PollQueues(redis *RedisInstance, timer *Timer, queueNames []QueueName, timeoutSecond, ttrSecond uint32) (queueName *QueueName, jobID string, err error) {
 	defer func() {
@@ -174,6 +233,14 @@ func PollQueues(redis *RedisInstance, timer *Timer, queueNames []QueueName, time
 		}).Error("Failed to add job to timer for ttr")
 		return queueName, jobID, err
 	}
+	if store := jobHistoryStoreFor(redis); store != nil {
+		if err := store.RecordStarted(queueName.Namespace, queueName.Queue, jobID); err != nil {
+			logger.WithField("err", err).Error("Failed to record job history for start")
+		}
+	}
+	if err := RecordQueueActivity(redis, queueName.Namespace, queueName.Queue, "consume"); err != nil {
+		logger.WithField("err", err).Error("Failed to record queue consume activity")
+	}
 	return queueName, jobID, nil
 }
 
@@ -0,0 +1,76 @@
+package redis
+
+import (
+	"time"
+
+	go_redis "github.com/go-redis/redis"
+	"github.com/meitu/lmstfy/engine"
+)
+
+// KnownQueuesPrefix holds, per namespace, the set of queue names that have
+// ever been pushed to. ListQueues reads this instead of scanning the
+// `q/<ns>/*` keyspace with KEYS/SCAN.
+const KnownQueuesPrefix = "known_queues"
+
+func knownQueuesKey(namespace string) string {
+	return join(KnownQueuesPrefix, namespace)
+}
+
+func queueActivityKey(namespace, queue, action string) string {
+	return join("queue_activity", namespace, queue, action)
+}
+
+// RecordQueueKnown registers queue under namespace so it shows up in
+// ListQueues, even before RecordQueueActivity has been called for it.
+func RecordQueueKnown(redis *RedisInstance, namespace, queue string) error {
+	return redis.Conn.SAdd(knownQueuesKey(namespace), queue).Err()
+}
+
+// RecordQueueActivity stamps the last time namespace/queue saw the given
+// action ("publish" or "consume"), surfaced by ListQueues.
+func RecordQueueActivity(redis *RedisInstance, namespace, queue, action string) error {
+	return redis.Conn.Set(queueActivityKey(namespace, queue, action), time.Now().Unix(), 0).Err()
+}
+
+func queueLastActivity(redis *RedisInstance, namespace, queue, action string) int64 {
+	val, err := redis.Conn.Get(queueActivityKey(namespace, queue, action)).Int64()
+	if err != nil && err != go_redis.Nil {
+		logger.WithField("err", err).Error("Failed to read queue activity timestamp")
+	}
+	return val
+}
+
+// ListQueues returns every queue namespace has ever pushed to, along with
+// its ready/delayed/deadletter sizes and last publish/consume timestamps.
+func ListQueues(redis *RedisInstance, timer *Timer, namespace string) ([]engine.QueueInfo, error) {
+	names, err := redis.Conn.SMembers(knownQueuesKey(namespace)).Result()
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]engine.QueueInfo, 0, len(names))
+	for _, name := range names {
+		q := NewQueue(namespace, name, redis, timer)
+		readySize, err := q.Size()
+		if err != nil {
+			return nil, err
+		}
+		delayedSize, err := timer.Size(namespace, name)
+		if err != nil {
+			return nil, err
+		}
+		dl := NewDeadLetter(namespace, name, redis, timer)
+		deadLetterSize, _, err := dl.Peek()
+		if err != nil && err != engine.ErrNotFound {
+			return nil, err
+		}
+		infos = append(infos, engine.QueueInfo{
+			Queue:           name,
+			ReadySize:       readySize,
+			DelayedSize:     delayedSize,
+			DeadLetterSize:  deadLetterSize,
+			LastPublishedAt: queueLastActivity(redis, namespace, name, "publish"),
+			LastConsumedAt:  queueLastActivity(redis, namespace, name, "consume"),
+		})
+	}
+	return infos, nil
+}
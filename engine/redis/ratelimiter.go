@@ -0,0 +1,166 @@
+package redis
+
+import (
+	"sync"
+	"time"
+
+	"github.com/meitu/lmstfy/engine"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/time/rate"
+)
+
+// rateLimitAction distinguishes publish admission from consume admission,
+// since the two are budgeted independently per namespace.
+type rateLimitAction string
+
+const (
+	rateLimitPublish rateLimitAction = "publish"
+	rateLimitConsume rateLimitAction = "consume"
+)
+
+var (
+	rateLimitQueueDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "lmstfy",
+		Subsystem: "engine_redis",
+		Name:      "rate_limit_queue_depth",
+		Help:      "current number of callers admitted into the rate limiter and still being served",
+	}, []string{"namespace", "queue", "action"})
+
+	rateLimitWaitSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "lmstfy",
+		Subsystem: "engine_redis",
+		Name:      "rate_limit_wait_seconds",
+		Help:      "time callers spent blocked in the rate limiter before being admitted",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"namespace", "queue", "action"})
+)
+
+// RateLimitConfig holds the per-namespace admission limits for publish and
+// consume calls. It's sourced from the namespace's token config, the same
+// place queue ttl/ttr/tries defaults come from.
+type RateLimitConfig struct {
+	PublishLimit int // max publish calls per second
+	PublishBurst int // publish burst size
+
+	ConsumeLimit int // max consume calls per second
+	ConsumeBurst int // consume burst size
+
+	WaitTimeout time.Duration // how long a caller may block before being rejected
+}
+
+// RateLimiter admits Publish/Consume calls through a token bucket keyed on
+// (namespace, queue). It lives in the engine layer, not the HTTP handlers,
+// so non-HTTP callers of the redis engine are throttled the same way.
+type RateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*rate.Limiter
+	configs map[string]RateLimitConfig // namespace -> config override
+
+	fallback RateLimitConfig
+}
+
+// NewRateLimiter creates a RateLimiter that falls back to fallback for any
+// namespace that hasn't been given an explicit config via SetNamespaceConfig.
+func NewRateLimiter(fallback RateLimitConfig) *RateLimiter {
+	return &RateLimiter{
+		buckets:  make(map[string]*rate.Limiter),
+		configs:  make(map[string]RateLimitConfig),
+		fallback: fallback,
+	}
+}
+
+// rateLimiters lets NewQueue attach the right RateLimiter to every Queue it
+// builds, by redis pool name, without every call site having to remember to
+// call Queue.SetRateLimiter itself.
+var rateLimiters = struct {
+	mu     sync.RWMutex
+	byPool map[string]*RateLimiter
+}{byPool: make(map[string]*RateLimiter)}
+
+// RegisterRateLimiter installs the rate limiter used by pool poolName. It
+// should be called once per pool at startup, after loading the pool's
+// namespace configs via SetNamespaceConfig. Every Queue subsequently built
+// with NewQueue for that pool is admission-gated by it.
+func RegisterRateLimiter(poolName string, limiter *RateLimiter) {
+	rateLimiters.mu.Lock()
+	defer rateLimiters.mu.Unlock()
+	rateLimiters.byPool[poolName] = limiter
+}
+
+func rateLimiterFor(redis *RedisInstance) *RateLimiter {
+	rateLimiters.mu.RLock()
+	defer rateLimiters.mu.RUnlock()
+	return rateLimiters.byPool[redis.Name]
+}
+
+// SetNamespaceConfig installs a per-namespace override, populated from the
+// existing token/namespace config loader.
+func (r *RateLimiter) SetNamespaceConfig(namespace string, cfg RateLimitConfig) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.configs[namespace] = cfg
+}
+
+func (r *RateLimiter) configFor(namespace string) RateLimitConfig {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if cfg, ok := r.configs[namespace]; ok {
+		return cfg
+	}
+	return r.fallback
+}
+
+func (r *RateLimiter) bucketFor(namespace, queue string, action rateLimitAction) *rate.Limiter {
+	key := join(namespace, queue, string(action))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if b, ok := r.buckets[key]; ok {
+		return b
+	}
+	// r.mu is already held here, so look the config up directly rather than
+	// through configFor, which would lock it again.
+	cfg, ok := r.configs[namespace]
+	if !ok {
+		cfg = r.fallback
+	}
+	var limit rate.Limit
+	var burst int
+	switch action {
+	case rateLimitPublish:
+		limit, burst = rate.Limit(cfg.PublishLimit), cfg.PublishBurst
+	case rateLimitConsume:
+		limit, burst = rate.Limit(cfg.ConsumeLimit), cfg.ConsumeBurst
+	}
+	b = rate.NewLimiter(limit, burst)
+	r.buckets[key] = b
+	return b
+}
+
+// Admit blocks the caller until a token is available for (namespace, queue,
+// action). If the wait would exceed the namespace's WaitTimeout, it gives up
+// immediately and returns a RateLimitedError carrying how long the caller
+// should back off.
+func (r *RateLimiter) Admit(namespace, queue string, action rateLimitAction) error {
+	limiter := r.bucketFor(namespace, queue, action)
+	cfg := r.configFor(namespace)
+
+	depth := rateLimitQueueDepth.WithLabelValues(namespace, queue, string(action))
+	depth.Inc()
+	defer depth.Dec()
+
+	startWait := time.Now()
+	reservation := limiter.Reserve()
+	if !reservation.OK() {
+		return &engine.RateLimitedError{RetryAfter: cfg.WaitTimeout}
+	}
+	delay := reservation.Delay()
+	if delay > cfg.WaitTimeout {
+		reservation.Cancel()
+		return &engine.RateLimitedError{RetryAfter: delay}
+	}
+	time.Sleep(delay)
+	rateLimitWaitSeconds.WithLabelValues(namespace, queue, string(action)).Observe(time.Since(startWait).Seconds())
+	return nil
+}
@@ -0,0 +1,53 @@
+package redis
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/meitu/lmstfy/engine"
+)
+
+func TestRateLimiter_AdmitWithinBurst(t *testing.T) {
+	r := NewRateLimiter(RateLimitConfig{
+		PublishLimit: 10,
+		PublishBurst: 2,
+		WaitTimeout:  time.Second,
+	})
+	for i := 0; i < 2; i++ {
+		if err := r.Admit("ns", "q", rateLimitPublish); err != nil {
+			t.Fatalf("call %d: expected burst to admit immediately, got %v", i, err)
+		}
+	}
+}
+
+func TestRateLimiter_AdmitBeyondWaitTimeoutIsRateLimited(t *testing.T) {
+	r := NewRateLimiter(RateLimitConfig{
+		PublishLimit: 1,
+		PublishBurst: 1,
+		WaitTimeout:  time.Millisecond,
+	})
+	if err := r.Admit("ns", "q", rateLimitPublish); err != nil {
+		t.Fatalf("first call should consume the burst token, got %v", err)
+	}
+	err := r.Admit("ns", "q", rateLimitPublish)
+	if err == nil {
+		t.Fatal("expected second call to exceed WaitTimeout and be rejected")
+	}
+	var rateLimited *engine.RateLimitedError
+	if !errors.As(err, &rateLimited) {
+		t.Fatalf("expected a *engine.RateLimitedError, got %T: %v", err, err)
+	}
+}
+
+func TestRateLimiter_NamespaceConfigOverridesFallback(t *testing.T) {
+	r := NewRateLimiter(RateLimitConfig{PublishLimit: 1, PublishBurst: 1})
+	r.SetNamespaceConfig("ns", RateLimitConfig{PublishLimit: 100, PublishBurst: 100})
+
+	if cfg := r.configFor("ns"); cfg.PublishBurst != 100 {
+		t.Fatalf("expected namespace override, got burst %d", cfg.PublishBurst)
+	}
+	if cfg := r.configFor("other"); cfg.PublishBurst != 1 {
+		t.Fatalf("expected fallback for unconfigured namespace, got burst %d", cfg.PublishBurst)
+	}
+}
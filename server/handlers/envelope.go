@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorCode is a stable, machine-readable identifier for a v2 error
+// response, so clients can branch on it instead of parsing the message.
+type ErrorCode string
+
+const (
+	ErrCodeInvalidDelay ErrorCode = "invalid_delay"
+	ErrCodeInvalidTTL   ErrorCode = "invalid_ttl"
+	ErrCodeInvalidTries ErrorCode = "invalid_tries"
+	ErrCodeBadRequest   ErrorCode = "bad_request"
+	ErrCodeWrongQueue   ErrorCode = "wrong_queue"
+	ErrCodeJobNotFound  ErrorCode = "job_not_found"
+	ErrCodeRateLimited  ErrorCode = "rate_limited"
+	ErrCodeInternal     ErrorCode = "internal_error"
+)
+
+// v2Error writes the v2 JSON error envelope: {error: {code, message,
+// request_id}}. It replaces the ad-hoc {"error": "..."} shape the v1
+// handlers still use.
+func v2Error(c *gin.Context, status int, code ErrorCode, message string) {
+	c.JSON(status, gin.H{
+		"error": gin.H{
+			"code":       code,
+			"message":    message,
+			"request_id": requestID(c),
+		},
+	})
+}
+
+// requestID returns the per-request ID already attached to the gin context
+// (the same one GetHTTPLogger includes in its log fields), or "" if none was
+// set.
+func requestID(c *gin.Context) string {
+	if id, ok := c.Get("request_id"); ok {
+		if s, ok := id.(string); ok {
+			return s
+		}
+	}
+	return ""
+}
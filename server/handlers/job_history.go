@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/meitu/lmstfy/engine"
+	"github.com/sirupsen/logrus"
+)
+
+// GET /:namespace/:queue/job/:job_id/history
+func JobHistory(c *gin.Context) {
+	logger := GetHTTPLogger(c)
+	e := c.MustGet("engine").(engine.Engine)
+	namespace := c.Param("namespace")
+	queue := c.Param("queue")
+	jobID := c.Param("job_id")
+
+	history, err := e.JobHistory(namespace, queue, jobID)
+	if err != nil {
+		if err == engine.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "job history not found"})
+			return
+		}
+		logger.WithFields(logrus.Fields{
+			"err":       err,
+			"namespace": namespace,
+			"queue":     queue,
+			"job_id":    jobID,
+		}).Error("Failed to get job history")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"namespace":   namespace,
+		"queue":       queue,
+		"job_id":      jobID,
+		"enqueued_at": history.EnqueuedAt,
+		"started_at":  history.StartedAt,
+		"finished_at": history.FinishedAt,
+		"status":      history.Status,
+		"attempts":    history.Attempts,
+		"last_error":  history.LastError,
+	})
+}
+
+// GET /:namespace/:queue/jobs
+// @query:
+//  - status: one of pending, in_flight, succeeded, dead, destroyed
+//  - limit:  int64
+//  - cursor: uint64, omit or 0 to start a fresh page; pass back next_cursor
+//            from the previous response to keep paging, until it comes back 0
+func JobsByStatus(c *gin.Context) {
+	logger := GetHTTPLogger(c)
+	e := c.MustGet("engine").(engine.Engine)
+	namespace := c.Param("namespace")
+	queue := c.Param("queue")
+	status := c.Query("status")
+	if status == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status is required"})
+		return
+	}
+
+	limitStr := c.DefaultQuery("limit", "20")
+	limit, err := strconv.ParseInt(limitStr, 10, 64)
+	if limit <= 0 || err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+
+	cursor, err := strconv.ParseUint(c.DefaultQuery("cursor", "0"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+		return
+	}
+
+	jobIDs, nextCursor, err := e.JobsByStatus(namespace, queue, engine.JobStatus(status), cursor, limit)
+	if err != nil {
+		logger.WithFields(logrus.Fields{
+			"err":       err,
+			"namespace": namespace,
+			"queue":     queue,
+			"status":    status,
+		}).Error("Failed to list jobs by status")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"namespace":   namespace,
+		"queue":       queue,
+		"status":      status,
+		"job_ids":     jobIDs,
+		"next_cursor": nextCursor,
+	})
+}
+
+// PUT /:namespace/:queue/job/:job_id/ack
+// Marks a consumed job as succeeded in its history record. This does not
+// touch the job's TTR timer entry; it's purely bookkeeping for the history
+// endpoints above.
+func AckJob(c *gin.Context) {
+	logger := GetHTTPLogger(c)
+	e := c.MustGet("engine").(engine.Engine)
+	namespace := c.Param("namespace")
+	queue := c.Param("queue")
+	jobID := c.Param("job_id")
+
+	if err := e.AckJob(namespace, queue, jobID); err != nil {
+		logger.WithFields(logrus.Fields{
+			"err":       err,
+			"namespace": namespace,
+			"queue":     queue,
+			"job_id":    jobID,
+		}).Error("Failed to ack job")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// PUT /:namespace/:queue/job/:job_id/nack
+// @query:
+//  - dead:       bool, whether the job has exhausted its tries and landed
+//                in the dead letter (default false, i.e. it'll be retried)
+//  - last_error: string, the consumer-supplied failure reason
+func NackJob(c *gin.Context) {
+	logger := GetHTTPLogger(c)
+	e := c.MustGet("engine").(engine.Engine)
+	namespace := c.Param("namespace")
+	queue := c.Param("queue")
+	jobID := c.Param("job_id")
+	lastError := c.Query("last_error")
+
+	dead, err := strconv.ParseBool(c.DefaultQuery("dead", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid dead"})
+		return
+	}
+
+	if err := e.NackJob(namespace, queue, jobID, lastError, dead); err != nil {
+		logger.WithFields(logrus.Fields{
+			"err":       err,
+			"namespace": namespace,
+			"queue":     queue,
+			"job_id":    jobID,
+			"dead":      dead,
+		}).Error("Failed to nack job")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
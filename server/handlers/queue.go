@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"math"
 	"net/http"
 	"strconv"
@@ -11,6 +12,13 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// respondRateLimited writes the 429 response for a RateLimitedError, telling
+// the caller via the Retry-After header how long to back off.
+func respondRateLimited(c *gin.Context, err *engine.RateLimitedError) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(err.RetryAfter.Seconds()))))
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": "rate limited"})
+}
+
 // PUT /:namespace/:queue
 // @query:
 //  - delay: uint32
@@ -64,6 +72,11 @@ func Publish(c *gin.Context) {
 
 	jobID, err := e.Publish(namespace, queue, body, uint32(ttlSecond), uint32(delaySecond), uint16(tries))
 	if err != nil {
+		var rateLimited *engine.RateLimitedError
+		if errors.As(err, &rateLimited) {
+			respondRateLimited(c, rateLimited)
+			return
+		}
 		logger.WithFields(logrus.Fields{
 			"err":       err,
 			"namespace": namespace,
@@ -128,6 +141,11 @@ func Consume(c *gin.Context) {
 	case 1:
 		job, err = e.Consume(namespace, queueList[0], uint32(ttrSecond), uint32(timeoutSecond))
 		if err != nil {
+			var rateLimited *engine.RateLimitedError
+			if errors.As(err, &rateLimited) {
+				respondRateLimited(c, rateLimited)
+				return
+			}
 			logger.WithField("err", err).Error("Failed to consume")
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
 			return
@@ -139,6 +157,11 @@ func Consume(c *gin.Context) {
 		}
 		job, err = e.ConsumeMulti(namespace, queueList, uint32(ttrSecond), uint32(timeoutSecond))
 		if err != nil {
+			var rateLimited *engine.RateLimitedError
+			if errors.As(err, &rateLimited) {
+				respondRateLimited(c, rateLimited)
+				return
+			}
 			logger.WithField("err", err).Error("Failed to consume")
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
 			return
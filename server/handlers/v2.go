@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/meitu/lmstfy/engine"
+	"github.com/sirupsen/logrus"
+)
+
+// mapEngineError translates a v1 engine error into the v2 status/code/message
+// triple. Unrecognized errors fall back to a generic internal error.
+func mapEngineError(err error) (status int, code ErrorCode, message string) {
+	var rateLimited *engine.RateLimitedError
+	switch {
+	case errors.As(err, &rateLimited):
+		return http.StatusTooManyRequests, ErrCodeRateLimited, err.Error()
+	case errors.Is(err, engine.ErrWrongQueue):
+		return http.StatusBadRequest, ErrCodeWrongQueue, err.Error()
+	case errors.Is(err, engine.ErrNotFound):
+		return http.StatusNotFound, ErrCodeJobNotFound, "job not found"
+	default:
+		return http.StatusInternalServerError, ErrCodeInternal, "internal error"
+	}
+}
+
+// PUT /v2/:namespace/:queue
+// Same semantics as the v1 Publish, but replies with the v2 error envelope.
+func V2Publish(c *gin.Context) {
+	logger := GetHTTPLogger(c)
+	e := c.MustGet("engine").(engine.Engine)
+	namespace := c.Param("namespace")
+	queue := c.Param("queue")
+
+	delaySecond, err := strconv.ParseUint(c.DefaultQuery("delay", DefaultDelay), 10, 32)
+	if err != nil {
+		v2Error(c, http.StatusBadRequest, ErrCodeInvalidDelay, "invalid delay")
+		return
+	}
+	ttlSecond, err := strconv.ParseUint(c.DefaultQuery("ttl", DefaultTTL), 10, 32)
+	if err != nil {
+		v2Error(c, http.StatusBadRequest, ErrCodeInvalidTTL, "invalid ttl")
+		return
+	}
+	if ttlSecond > 0 && ttlSecond < delaySecond {
+		v2Error(c, http.StatusBadRequest, ErrCodeInvalidTTL, "ttl is shorter than delay")
+		return
+	}
+	tries, err := strconv.ParseUint(c.DefaultQuery("tries", DefaultTries), 10, 16)
+	if err != nil || tries == 0 {
+		v2Error(c, http.StatusBadRequest, ErrCodeInvalidTries, "invalid tries")
+		return
+	}
+
+	body, err := c.GetRawData()
+	if err != nil {
+		v2Error(c, http.StatusBadRequest, ErrCodeBadRequest, "failed to read body")
+		return
+	}
+	if len(body) > math.MaxUint16 {
+		v2Error(c, http.StatusRequestEntityTooLarge, ErrCodeBadRequest, "body too large")
+		return
+	}
+
+	jobID, err := e.Publish(namespace, queue, body, uint32(ttlSecond), uint32(delaySecond), uint16(tries))
+	if err != nil {
+		status, code, message := mapEngineError(err)
+		logger.WithFields(logrus.Fields{"err": err, "namespace": namespace, "queue": queue}).Error("Failed to publish")
+		v2Error(c, status, code, message)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"msg": "published", "job_id": jobID})
+}
+
+// GET /v2/:namespace/:queue[,:queue]*
+// Same semantics as the v1 Consume, but replies with the v2 error envelope.
+func V2Consume(c *gin.Context) {
+	logger := GetHTTPLogger(c)
+	e := c.MustGet("engine").(engine.Engine)
+	namespace := c.Param("namespace")
+	var queueList []string
+	for _, q := range strings.Split(c.Param("queue"), ",") {
+		if q != "" {
+			queueList = append(queueList, q)
+		}
+	}
+	if len(queueList) == 0 {
+		v2Error(c, http.StatusBadRequest, ErrCodeBadRequest, "invalid queue name(s)")
+		return
+	}
+
+	ttrSecond, err := strconv.ParseUint(c.DefaultQuery("ttr", DefaultTTR), 10, 32)
+	if err != nil {
+		v2Error(c, http.StatusBadRequest, ErrCodeBadRequest, "invalid ttr")
+		return
+	}
+	timeoutSecond, err := strconv.ParseUint(c.DefaultQuery("timeout", DefaultTimeout), 10, 32)
+	if err != nil {
+		v2Error(c, http.StatusBadRequest, ErrCodeBadRequest, "invalid timeout")
+		return
+	}
+
+	var job engine.Job
+	if len(queueList) == 1 {
+		job, err = e.Consume(namespace, queueList[0], uint32(ttrSecond), uint32(timeoutSecond))
+	} else {
+		if timeoutSecond == 0 {
+			v2Error(c, http.StatusBadRequest, ErrCodeBadRequest, "timeout must be provided to consume multiple queues")
+			return
+		}
+		job, err = e.ConsumeMulti(namespace, queueList, uint32(ttrSecond), uint32(timeoutSecond))
+	}
+	if err != nil {
+		status, code, message := mapEngineError(err)
+		logger.WithField("err", err).Error("Failed to consume")
+		v2Error(c, status, code, message)
+		return
+	}
+	if job == nil {
+		v2Error(c, http.StatusNotFound, ErrCodeJobNotFound, "no job available")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"namespace":  namespace,
+		"queue":      job.Queue(),
+		"job_id":     job.ID(),
+		"data":       job.Body(),
+		"ttl":        job.TTL(),
+		"elapsed_ms": job.ElapsedMS(),
+	})
+}
+
+// DELETE /v2/:namespace/:queue/job/:job_id
+// Same semantics as the v1 Delete, but replies with the v2 error envelope.
+func V2Delete(c *gin.Context) {
+	logger := GetHTTPLogger(c)
+	e := c.MustGet("engine").(engine.Engine)
+	namespace := c.Param("namespace")
+	queue := c.Param("queue")
+	jobID := c.Param("job_id")
+
+	if err := e.Delete(namespace, queue, jobID); err != nil {
+		status, code, message := mapEngineError(err)
+		logger.WithFields(logrus.Fields{"err": err, "namespace": namespace, "queue": queue, "job_id": jobID}).Error("Failed to delete")
+		v2Error(c, status, code, message)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GET /v2/:namespace/queues
+// Lists every queue the namespace has ever published to, with its current
+// ready/delayed/deadletter sizes and last publish/consume timestamps.
+func V2ListQueues(c *gin.Context) {
+	logger := GetHTTPLogger(c)
+	e := c.MustGet("engine").(engine.Engine)
+	namespace := c.Param("namespace")
+
+	queues, err := e.ListQueues(namespace)
+	if err != nil {
+		status, code, message := mapEngineError(err)
+		logger.WithFields(logrus.Fields{"err": err, "namespace": namespace}).Error("Failed to list queues")
+		v2Error(c, status, code, message)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"namespace": namespace,
+		"queues":    queues,
+	})
+}
+
+// GET /v2/job-types/:namespace
+// Returns each queue's default ttl/ttr/tries and rate-limit config, so
+// clients can self-configure instead of guessing.
+func V2JobTypes(c *gin.Context) {
+	logger := GetHTTPLogger(c)
+	e := c.MustGet("engine").(engine.Engine)
+	namespace := c.Param("namespace")
+
+	jobTypes, err := e.JobTypes(namespace)
+	if err != nil {
+		status, code, message := mapEngineError(err)
+		logger.WithFields(logrus.Fields{"err": err, "namespace": namespace}).Error("Failed to get job types")
+		v2Error(c, status, code, message)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"namespace": namespace,
+		"job_types": jobTypes,
+	})
+}
@@ -0,0 +1,33 @@
+package server
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/meitu/lmstfy/engine"
+	"github.com/meitu/lmstfy/engine/redis"
+)
+
+// PoolConfig is the startup configuration for one redis pool: its
+// connection/timer handles, the base engine implementing the
+// publish/consume/delete/dead-letter lifecycle, and the namespace rate-limit
+// budgets loaded from that pool's token config.
+type PoolConfig struct {
+	Base                redis.BaseEngine
+	Redis               *redis.RedisInstance
+	Timer               *redis.Timer
+	FallbackRateLimit   redis.RateLimitConfig
+	NamespaceRateLimits redis.NamespaceRateLimits
+	JobDefaults         engine.QueueTypeInfo
+}
+
+// EngineMiddleware is the real call site for redis.SetupPool: it builds this
+// pool's RateLimiter and JobHistoryStore from cfg once at startup -- which is
+// what actually registers them so NewQueue/PollQueues pick them up -- and
+// attaches the resulting Engine to every request's gin context under
+// "engine", where the handlers in server/handlers expect to find it.
+func EngineMiddleware(cfg PoolConfig) gin.HandlerFunc {
+	e := redis.SetupPool(cfg.Base, cfg.Redis, cfg.Timer, cfg.FallbackRateLimit, cfg.NamespaceRateLimits, cfg.JobDefaults)
+	return func(c *gin.Context) {
+		c.Set("engine", engine.Engine(e))
+		c.Next()
+	}
+}
@@ -0,0 +1,43 @@
+package server
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/meitu/lmstfy/server/handlers"
+)
+
+// RegisterRoutes mounts the v1 and v2 HTTP surface onto router. It assumes
+// every request already has an "engine" value in its gin context (see
+// EngineMiddleware) for the handlers to pick up via c.MustGet("engine").
+func RegisterRoutes(router gin.IRouter) {
+	registerV1Routes(router)
+	registerV2Routes(router.Group("/v2"))
+}
+
+func registerV1Routes(router gin.IRouter) {
+	router.PUT("/:namespace/:queue", handlers.Publish)
+	// NOTE: Consume accepts a comma-separated list of queues in this segment,
+	// so the param would be better named `queues`, but gin requires it to
+	// share the `:queue` wildcard name with the other routes at this position.
+	router.GET("/:namespace/:queue", handlers.Consume)
+	router.DELETE("/:namespace/:queue", handlers.DestroyQueue)
+	router.GET("/:namespace/:queue/peek", handlers.PeekQueue)
+	router.GET("/:namespace/:queue/size", handlers.Size)
+	router.GET("/:namespace/:queue/deadletter", handlers.PeekDeadLetter)
+	router.PUT("/:namespace/:queue/deadletter", handlers.RespawnDeadLetter)
+	router.DELETE("/:namespace/:queue/deadletter", handlers.DeleteDeadLetter)
+
+	router.GET("/:namespace/:queue/jobs", handlers.JobsByStatus)
+	router.GET("/:namespace/:queue/job/:job_id", handlers.PeekJob)
+	router.DELETE("/:namespace/:queue/job/:job_id", handlers.Delete)
+	router.GET("/:namespace/:queue/job/:job_id/history", handlers.JobHistory)
+	router.PUT("/:namespace/:queue/job/:job_id/ack", handlers.AckJob)
+	router.PUT("/:namespace/:queue/job/:job_id/nack", handlers.NackJob)
+}
+
+func registerV2Routes(v2 gin.IRouter) {
+	v2.GET("/job-types/:namespace", handlers.V2JobTypes)
+	v2.GET("/:namespace/queues", handlers.V2ListQueues)
+	v2.PUT("/:namespace/:queue", handlers.V2Publish)
+	v2.GET("/:namespace/:queue", handlers.V2Consume)
+	v2.DELETE("/:namespace/:queue/job/:job_id", handlers.V2Delete)
+}